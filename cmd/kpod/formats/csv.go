@@ -0,0 +1,64 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// CSVstruct is a formats.Writer that renders Output as RFC 4180 CSV. Fields
+// titles each column from the same header map used by StdoutTemplate, unless
+// NoHeading is set.
+type CSVstruct struct {
+	Output    []interface{}
+	Fields    map[string]string
+	NoHeading bool
+}
+
+// Out renders c.Output as CSV to stdout, quoting fields that contain commas,
+// quotes, or newlines per RFC 4180.
+func (c CSVstruct) Out() error {
+	w := csv.NewWriter(os.Stdout)
+
+	if len(c.Output) == 0 {
+		w.Flush()
+		return w.Error()
+	}
+
+	fieldNames := csvFieldNames(c.Output[0])
+
+	if !c.NoHeading {
+		header := make([]string, len(fieldNames))
+		for i, name := range fieldNames {
+			header[i] = strings.TrimSpace(c.Fields[name])
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range c.Output {
+		v := reflect.Indirect(reflect.ValueOf(item))
+		row := make([]string, len(fieldNames))
+		for i := range fieldNames {
+			row[i] = fmt.Sprintf("%v", v.Field(i).Interface())
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func csvFieldNames(item interface{}) []string {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	names := make([]string, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		names[i] = v.Type().Field(i).Name
+	}
+	return names
+}
@@ -0,0 +1,84 @@
+package formats
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+type csvRow struct {
+	Name string
+	Note string
+}
+
+func captureOut(t *testing.T, f func() error) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	os.Stdout = w
+
+	err = f()
+	w.Close()
+	os.Stdout = old
+	if err != nil {
+		t.Fatalf("Out() returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestCSVstructQuotesFieldsWithCommasAndQuotes(t *testing.T) {
+	c := CSVstruct{
+		Output:    []interface{}{csvRow{Name: "a,b", Note: `has "quotes"`}},
+		Fields:    map[string]string{"Name": "NAME", "Note": "NOTE"},
+		NoHeading: true,
+	}
+
+	got := captureOut(t, c.Out)
+	want := "\"a,b\",\"has \"\"quotes\"\"\"\n"
+	if got != want {
+		t.Fatalf("Out() = %q, want %q", got, want)
+	}
+}
+
+func TestCSVstructHeadingUsesFieldMap(t *testing.T) {
+	c := CSVstruct{
+		Output: []interface{}{csvRow{Name: "a", Note: "b"}},
+		Fields: map[string]string{"Name": "NAME   ", "Note": "NOTE   "},
+	}
+
+	got := captureOut(t, c.Out)
+	want := "NAME,NOTE\na,b\n"
+	if got != want {
+		t.Fatalf("Out() = %q, want %q", got, want)
+	}
+}
+
+func TestCSVstructNoHeadingOmitsHeader(t *testing.T) {
+	c := CSVstruct{
+		Output:    []interface{}{csvRow{Name: "a", Note: "b"}},
+		Fields:    map[string]string{"Name": "NAME", "Note": "NOTE"},
+		NoHeading: true,
+	}
+
+	got := captureOut(t, c.Out)
+	want := "a,b\n"
+	if got != want {
+		t.Fatalf("Out() = %q, want %q", got, want)
+	}
+}
+
+func TestCSVstructEmptyOutputWritesNothing(t *testing.T) {
+	c := CSVstruct{Output: nil, Fields: map[string]string{}}
+
+	got := captureOut(t, c.Out)
+	if got != "" {
+		t.Fatalf("Out() on empty Output = %q, want empty", got)
+	}
+}
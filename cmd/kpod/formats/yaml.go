@@ -0,0 +1,36 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// YAMLstruct is a formats.Writer that renders Output as a YAML list of maps.
+// Output is round-tripped through JSON first so the emitted keys match the
+// `json` struct tags rather than Go's default (lower-cased) YAML field names.
+type YAMLstruct struct {
+	Output []interface{}
+}
+
+// Out renders y.Output as YAML to stdout.
+func (y YAMLstruct) Out() error {
+	data, err := json.Marshal(y.Output)
+	if err != nil {
+		return err
+	}
+
+	var generic []map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(out))
+	return nil
+}
@@ -0,0 +1,27 @@
+package formats
+
+import "testing"
+
+type yamlRow struct {
+	Name string `json:"name"`
+}
+
+func TestYAMLstructUsesJSONTagNames(t *testing.T) {
+	y := YAMLstruct{Output: []interface{}{yamlRow{Name: "a"}}}
+
+	got := captureOut(t, y.Out)
+	want := "- name: a\n"
+	if got != want {
+		t.Fatalf("Out() = %q, want %q", got, want)
+	}
+}
+
+func TestYAMLstructEmptyOutput(t *testing.T) {
+	y := YAMLstruct{Output: nil}
+
+	got := captureOut(t, y.Out)
+	want := "[]\n"
+	if got != want {
+		t.Fatalf("Out() on empty Output = %q, want %q", got, want)
+	}
+}
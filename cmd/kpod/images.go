@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/containers/storage"
 	"github.com/kubernetes-incubator/cri-o/cmd/kpod/formats"
@@ -13,6 +17,10 @@ import (
 	"github.com/urfave/cli"
 )
 
+const (
+	none = "<none>"
+)
+
 var (
 	imagesFlags = []cli.Flag{
 		cli.BoolFlag{
@@ -33,11 +41,20 @@ var (
 		},
 		cli.StringFlag{
 			Name:  "format",
-			Usage: "Change the output format.",
+			Usage: "Change the output format, valid formats include: json, yaml, csv, or a Go template.",
 		},
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:  "filter, f",
-			Usage: "filter output based on conditions provided (default [])",
+			Usage: "filter output based on conditions provided (may be used multiple times)",
+		},
+		cli.BoolFlag{
+			Name:  "no-digests-fetch",
+			Usage: "do not resolve image manifests/digests when showing digests is not requested (ignored with --digests)",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Usage: "sort by created (default, descending), id, repository, tag, or size; prefix with '-' to reverse",
+			Value: "created",
 		},
 	}
 
@@ -78,6 +95,10 @@ func imagesCmd(c *cli.Context) error {
 	if c.IsSet("digests") {
 		digests = c.Bool("digests")
 	}
+	noDigestsFetch := false
+	if c.IsSet("no-digests-fetch") {
+		noDigestsFetch = c.Bool("no-digests-fetch")
+	}
 	outputFormat := genImagesFormat(quiet, truncate, digests)
 	if c.IsSet("format") {
 		outputFormat = c.String("format")
@@ -92,7 +113,7 @@ func imagesCmd(c *cli.Context) error {
 
 	var params *libkpodimage.FilterParams
 	if c.IsSet("filter") {
-		params, err = libkpodimage.ParseFilter(store, c.String("filter"))
+		params, err = libkpodimage.ParseFilter(store, c.StringSlice("filter"))
 		if err != nil {
 			return errors.Wrapf(err, "error parsing filter")
 		}
@@ -100,12 +121,12 @@ func imagesCmd(c *cli.Context) error {
 		params = nil
 	}
 
-	imageList, err := libkpodimage.GetImagesMatchingFilter(store, params, name)
+	imageList, resolved, err := libkpodimage.GetImagesMatchingFilter(store, params, name)
 	if err != nil {
 		return errors.Wrapf(err, "could not get list of images matching filter")
 	}
 
-	return outputImages(store, imageList, truncate, digests, quiet, outputFormat, noheading)
+	return outputImages(store, imageList, resolved, truncate, digests, quiet, noDigestsFetch, outputFormat, noheading, c.String("sort"))
 }
 
 func genImagesFormat(quiet, truncate, digests bool) (format string) {
@@ -117,7 +138,7 @@ func genImagesFormat(quiet, truncate, digests bool) (format string) {
 	} else {
 		format = "table {{ .ID | printf \"%-64s\" }} "
 	}
-	format += "{{ .Name | printf \"%-56s\" }} "
+	format += "{{ .Repository | printf \"%-28s\" }} {{ .Tag | printf \"%-16s\" }} "
 
 	if digests {
 		format += "{{ .Digest | printf \"%-71s \"}} "
@@ -127,43 +148,269 @@ func genImagesFormat(quiet, truncate, digests bool) (format string) {
 	return
 }
 
-func outputImages(store storage.Store, images []storage.Image, truncate, digests, quiet bool, outputFormat string, noheading bool) error {
-	imageOutput := []imageOutputParams{}
+// splitRepoAndTag splits a repo:tag image name the way `docker images` does;
+// an empty name renders as "<none>".
+func splitRepoAndTag(name string) (repository, tag string) {
+	if name == "" {
+		return none, none
+	}
+	repository = name
+	tag = "latest"
+	if idx := strings.LastIndex(name, ":"); idx >= 0 {
+		possibleTag := name[idx+1:]
+		if !strings.Contains(possibleTag, "/") {
+			repository = name[:idx]
+			tag = possibleTag
+		}
+	}
+	return repository, tag
+}
+
+// imageData is the per-image result of fetchImageData.
+type imageData struct {
+	digest    digest.Digest
+	createdAt time.Time
+	size      int64
+	err       error
+}
+
+// fetchImageData resolves the size (and, if digests is set, the digest and
+// authoritative created time) for a single image: reusing pre if a filter
+// already resolved it, else the cached top-layer size, else the full walk
+// (skipped, leaving the size at 0, when noDigestsFetch applies).
+func fetchImageData(store storage.Store, img storage.Image, digests, noDigestsFetch bool, pre libkpodimage.ResolvedInfo, havePre bool) imageData {
+	createdAt := img.Created
+
+	if havePre {
+		if pre.Info != nil {
+			createdAt = pre.Info.Created
+		}
+		imgDigest := pre.Digest
+		if !digests {
+			imgDigest = ""
+		}
+		return imageData{digest: imgDigest, createdAt: createdAt, size: pre.Size}
+	}
+
+	size, cached := libkpodimage.CachedSize(store, img)
+	if cached && !digests {
+		return imageData{createdAt: createdAt, size: size}
+	}
+	if !digests && noDigestsFetch {
+		return imageData{createdAt: createdAt, size: size}
+	}
+
+	info, imgDigest, size, err := libkpodimage.InfoAndDigestAndSize(store, img)
+	if err != nil {
+		return imageData{err: err}
+	}
+	if info != nil {
+		createdAt = info.Created
+	}
+	if !digests {
+		imgDigest = ""
+	}
+	return imageData{digest: imgDigest, createdAt: createdAt, size: size}
+}
+
+// fetchAllImageData gathers fetchImageData for every image in images,
+// parallelized across a worker pool bounded by runtime.NumCPU(), preserving
+// image order in the returned slice.
+func fetchAllImageData(store storage.Store, images []storage.Image, resolved map[string]libkpodimage.ResolvedInfo, digests, noDigestsFetch bool) ([]imageData, error) {
+	results := make([]imageData, len(images))
+
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, img storage.Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pre, ok := resolved[img.ID]
+			results[i] = fetchImageData(store, img, digests, noDigestsFetch, pre, ok)
+		}(i, img)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+	return results, nil
+}
+
+// imageRow pairs a source image with its gathered data.
+type imageRow struct {
+	img  storage.Image
+	data imageData
+}
 
+// tableRow is one rendered REPOSITORY:TAG line, sorted by its own
+// repository/tag rather than its image's first name.
+type tableRow struct {
+	params    imagesTemplateParams
+	createdAt time.Time
+	size      int64
+}
+
+// sortKeyLess builds a stable-sort "less" comparator for sortBy, one of
+// "created" (default, descending), "id", "repository", "tag", or "size",
+// optionally prefixed with "-" to reverse direction.
+func sortKeyLess(sortBy string, id, repository, tag func(i int) string, createdAt func(i int) time.Time, size func(i int) int64) func(i, j int) bool {
+	key := sortBy
+	reverse := false
+	if strings.HasPrefix(key, "-") {
+		reverse = true
+		key = key[1:]
+	}
+
+	defaultDescending := key == "created" || key == ""
+	ascending := func(i, j int) bool {
+		switch key {
+		case "id":
+			return id(i) < id(j)
+		case "repository":
+			return repository(i) < repository(j)
+		case "tag":
+			return tag(i) < tag(j)
+		case "size":
+			return size(i) < size(j)
+		default: // "created"
+			return createdAt(i).Before(createdAt(j))
+		}
+	}
+
+	if defaultDescending != reverse {
+		return func(i, j int) bool { return ascending(j, i) }
+	}
+	return ascending
+}
+
+// sortImageRows orders rows in place by sortBy; repository/tag keys use each
+// image's first name.
+func sortImageRows(rows []imageRow, sortBy string) {
+	less := sortKeyLess(sortBy,
+		func(i int) string { return rows[i].img.ID },
+		func(i int) string { r, _ := splitRepoAndTag(firstName(rows[i].img)); return r },
+		func(i int) string { _, t := splitRepoAndTag(firstName(rows[i].img)); return t },
+		func(i int) time.Time { return rows[i].data.createdAt },
+		func(i int) int64 { return rows[i].data.size },
+	)
+	sort.SliceStable(rows, less)
+}
+
+// sortTableRows orders rows in place by sortBy, like sortImageRows except
+// repository/tag keys use each row's own tag.
+func sortTableRows(rows []tableRow, sortBy string) {
+	less := sortKeyLess(sortBy,
+		func(i int) string { return rows[i].params.ID },
+		func(i int) string { return rows[i].params.Repository },
+		func(i int) string { return rows[i].params.Tag },
+		func(i int) time.Time { return rows[i].createdAt },
+		func(i int) int64 { return rows[i].size },
+	)
+	sort.SliceStable(rows, less)
+}
+
+func firstName(img storage.Image) string {
+	if len(img.Names) > 0 {
+		return img.Names[0]
+	}
+	return ""
+}
+
+// dedupeQuietRows projects already-sorted rows into one imagesTemplateParams
+// per image, dropping consecutive rows sharing an ID.
+func dedupeQuietRows(rows []imageRow) []imagesTemplateParams {
+	out := []imagesTemplateParams{}
 	lastID := ""
-	for _, img := range images {
-		if quiet && lastID == img.ID {
+	for _, row := range rows {
+		if lastID == row.img.ID {
 			continue // quiet should not show the same ID multiple times
 		}
-		createdTime := img.Created
+		out = append(out, imagesTemplateParams{ID: row.img.ID})
+		lastID = row.img.ID
+	}
+	return out
+}
 
-		name := ""
-		if len(img.Names) > 0 {
-			name = img.Names[0]
+// buildTableRows expands rows into one tableRow per tag.
+func buildTableRows(rows []imageRow) []tableRow {
+	out := []tableRow{}
+	for _, row := range rows {
+		names := row.img.Names
+		if len(names) == 0 {
+			names = []string{""}
 		}
-
-		info, imageDigest, size, _ := libkpodimage.InfoAndDigestAndSize(store, img)
-		if info != nil {
-			createdTime = info.Created
+		for _, name := range names {
+			repository, tag := splitRepoAndTag(name)
+			out = append(out, tableRow{
+				params: imagesTemplateParams{
+					ID:         row.img.ID,
+					Repository: repository,
+					Tag:        tag,
+					Digest:     row.data.digest,
+					CreatedAt:  row.data.createdAt.Format("Jan 2, 2006 15:04"),
+					Size:       libkpodimage.FormattedSize(row.data.size),
+				},
+				createdAt: row.data.createdAt,
+				size:      row.data.size,
+			})
 		}
+	}
+	return out
+}
+
+func outputImages(store storage.Store, images []storage.Image, resolved map[string]libkpodimage.ResolvedInfo, truncate, digests, quiet, noDigestsFetch bool, outputFormat string, noheading bool, sortBy string) error {
+	imageOutput := []imagesTemplateParams{}
+	jsonImageOutput := []imagesJSONParams{}
 
-		params := imageOutputParams{
-			ID:        img.ID,
-			Name:      name,
-			Digest:    imageDigest,
-			CreatedAt: createdTime.Format("Jan 2, 2006 15:04"),
-			Size:      libkpodimage.FormattedSize(size),
+	perImageData, err := fetchAllImageData(store, images, resolved, digests, noDigestsFetch)
+	if err != nil {
+		return errors.Wrapf(err, "could not gather image data")
+	}
+
+	rows := make([]imageRow, len(images))
+	for i, img := range images {
+		rows[i] = imageRow{img: img, data: perImageData[i]}
+	}
+	sortImageRows(rows, sortBy)
+
+	for _, row := range rows {
+		jsonImageOutput = append(jsonImageOutput, imagesJSONParams{
+			ID:        row.img.ID,
+			Names:     row.img.Names,
+			Digest:    row.data.digest,
+			CreatedAt: row.data.createdAt.Format("Jan 2, 2006 15:04"),
+			Size:      libkpodimage.FormattedSize(row.data.size),
+		})
+	}
+
+	if quiet {
+		imageOutput = dedupeQuietRows(rows)
+	} else {
+		tableRows := buildTableRows(rows)
+		sortTableRows(tableRows, sortBy)
+		for _, tr := range tableRows {
+			imageOutput = append(imageOutput, tr.params)
 		}
-		imageOutput = append(imageOutput, params)
 	}
 
+	// ps/history/info/inspect aren't part of this tree; they pick up yaml/csv
+	// for free once they add the same cases to their own format switch.
 	var out formats.Writer
 
 	switch outputFormat {
 	case "json":
-		out = formats.JSONstruct{Output: toGeneric(imageOutput)}
+		out = formats.JSONstruct{Output: toGenericJSON(jsonImageOutput)}
+	case "yaml":
+		out = formats.YAMLstruct{Output: toGenericJSON(jsonImageOutput)}
+	case "csv":
+		out = formats.CSVstruct{Output: toGeneric(imageOutput), Fields: (&imagesTemplateParams{}).headerMap(), NoHeading: noheading}
 	default:
-		out = formats.StdoutTemplate{Output: toGeneric(imageOutput), Template: outputFormat, Fields: imageOutput[0].headerMap()}
+		out = formats.StdoutTemplate{Output: toGeneric(imageOutput), Template: outputFormat, Fields: (&imagesTemplateParams{}).headerMap()}
 	}
 
 	formats.Writer(out).Out()
@@ -171,15 +418,26 @@ func outputImages(store storage.Store, images []storage.Image, truncate, digests
 	return nil
 }
 
-type imageOutputParams struct {
+// imagesTemplateParams feeds the table/template output, one entry per tag.
+type imagesTemplateParams struct {
+	ID         string
+	Repository string
+	Tag        string
+	Digest     digest.Digest
+	CreatedAt  string
+	Size       string
+}
+
+// imagesJSONParams feeds the JSON output, one entry per image.
+type imagesJSONParams struct {
 	ID        string        `json:"id"`
-	Name      string        `json:"names"`
+	Names     []string      `json:"names"`
 	Digest    digest.Digest `json:"digest"`
 	CreatedAt string        `json:"created"`
 	Size      string        `json:"size"`
 }
 
-func toGeneric(params []imageOutputParams) []interface{} {
+func toGeneric(params []imagesTemplateParams) []interface{} {
 	genericParams := make([]interface{}, len(params))
 	for i, v := range params {
 		genericParams[i] = interface{}(v)
@@ -187,17 +445,25 @@ func toGeneric(params []imageOutputParams) []interface{} {
 	return genericParams
 }
 
-func (i *imageOutputParams) headerMap() map[string]string {
+func toGenericJSON(params []imagesJSONParams) []interface{} {
+	genericParams := make([]interface{}, len(params))
+	for i, v := range params {
+		genericParams[i] = interface{}(v)
+	}
+	return genericParams
+}
+
+func (i *imagesTemplateParams) headerMap() map[string]string {
 	v := reflect.Indirect(reflect.ValueOf(i))
 	values := make(map[string]string)
 
 	for i := 0; i < v.NumField(); i++ {
 		key := v.Type().Field(i).Name
 		value := key
-		if value == "ID" || value == "Name" {
+		if value == "ID" {
 			value = "Image" + value
 		}
 		values[key] = fmt.Sprintf("%s        ", strings.ToUpper(splitCamelCase(value)))
 	}
 	return values
-}
\ No newline at end of file
+}
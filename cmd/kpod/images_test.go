@@ -0,0 +1,194 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containers/storage"
+)
+
+func row(id string, names []string, created time.Time, size int64) imageRow {
+	return imageRow{
+		img:  storage.Image{ID: id, Names: names, Created: created},
+		data: imageData{createdAt: created, size: size},
+	}
+}
+
+func ids(rows []imageRow) []string {
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		out[i] = r.img.ID
+	}
+	return out
+}
+
+func TestSortImageRowsCreatedDefaultsToDescending(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("old", []string{"a:latest"}, now.Add(-2*time.Hour), 10),
+		row("new", []string{"b:latest"}, now, 10),
+		row("mid", []string{"c:latest"}, now.Add(-1*time.Hour), 10),
+	}
+
+	sortImageRows(rows, "created")
+
+	want := []string{"new", "mid", "old"}
+	got := ids(rows)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("created sort = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortImageRowsReversePrefix(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("old", []string{"a:latest"}, now.Add(-2*time.Hour), 10),
+		row("new", []string{"b:latest"}, now, 10),
+	}
+
+	sortImageRows(rows, "-created")
+
+	want := []string{"old", "new"}
+	got := ids(rows)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("-created sort = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortImageRowsByID(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("b", []string{"b:latest"}, now, 1),
+		row("a", []string{"a:latest"}, now, 1),
+		row("c", []string{"c:latest"}, now, 1),
+	}
+
+	sortImageRows(rows, "id")
+
+	want := []string{"a", "b", "c"}
+	got := ids(rows)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("id sort = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortImageRowsBySize(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("big", []string{"big:latest"}, now, 300),
+		row("small", []string{"small:latest"}, now, 100),
+		row("mid", []string{"mid:latest"}, now, 200),
+	}
+
+	sortImageRows(rows, "size")
+
+	want := []string{"small", "mid", "big"}
+	got := ids(rows)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("size sort = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortImageRowsStableOnEqualKeys(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("first", []string{"same:latest"}, now, 1),
+		row("second", []string{"same:latest"}, now, 1),
+	}
+
+	sortImageRows(rows, "repository")
+
+	want := []string{"first", "second"}
+	got := ids(rows)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("equal-key sort = %v, want %v (expected insertion order preserved)", got, want)
+		}
+	}
+}
+
+func TestSortTableRowsByTagSortsPerTagNotPerImage(t *testing.T) {
+	now := time.Now()
+	// "multi" carries two tags, "b" and "z"; only sorting the expanded
+	// per-tag rows (not the pre-expansion image rows) can interleave them
+	// correctly with "solo", whose single tag is "m".
+	rows := []imageRow{
+		row("multi", []string{"repo:z", "repo:b"}, now, 1),
+		row("solo", []string{"repo:m"}, now, 1),
+	}
+
+	tableRows := buildTableRows(rows)
+	sortTableRows(tableRows, "tag")
+
+	var gotTags []string
+	for _, tr := range tableRows {
+		gotTags = append(gotTags, tr.params.Tag)
+	}
+	want := []string{"b", "m", "z"}
+	for i := range want {
+		if gotTags[i] != want[i] {
+			t.Fatalf("tag sort = %v, want %v", gotTags, want)
+		}
+	}
+}
+
+func TestSortTableRowsByRepository(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{
+		row("img1", []string{"zzz:latest"}, now, 1),
+		row("img2", []string{"aaa:latest"}, now, 1),
+	}
+
+	tableRows := buildTableRows(rows)
+	sortTableRows(tableRows, "repository")
+
+	want := []string{"aaa", "zzz"}
+	for i, tr := range tableRows {
+		if tr.params.Repository != want[i] {
+			t.Fatalf("repository sort = %q at %d, want %q", tr.params.Repository, i, want[i])
+		}
+	}
+}
+
+func TestDedupeQuietRowsAfterSort(t *testing.T) {
+	now := time.Now()
+	// Two entries share ID "dup"; only after sort.SliceStable by ID
+	// brings them adjacent can dedupeQuietRows collapse them.
+	rows := []imageRow{
+		row("dup", []string{"a:latest"}, now, 1),
+		row("other", []string{"b:latest"}, now, 1),
+		row("dup", []string{"a:old"}, now, 1),
+	}
+
+	sortImageRows(rows, "id")
+	out := dedupeQuietRows(rows)
+
+	if len(out) != 2 {
+		t.Fatalf("dedupeQuietRows returned %d rows, want 2: %+v", len(out), out)
+	}
+	if out[0].ID != "dup" || out[1].ID != "other" {
+		t.Fatalf("dedupeQuietRows = %+v, want [dup, other]", out)
+	}
+}
+
+func TestBuildTableRowsHandlesNoNames(t *testing.T) {
+	now := time.Now()
+	rows := []imageRow{row("untagged", nil, now, 1)}
+
+	tableRows := buildTableRows(rows)
+
+	if len(tableRows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(tableRows))
+	}
+	if tableRows[0].params.Repository != none || tableRows[0].params.Tag != none {
+		t.Fatalf("untagged image rendered as %q/%q, want %q/%q", tableRows[0].params.Repository, tableRows[0].params.Tag, none, none)
+	}
+}
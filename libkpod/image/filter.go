@@ -0,0 +1,336 @@
+package image
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containers/storage"
+	digest "github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ResolvedInfo is the manifest info InfoAndDigestAndSize resolves for an
+// image, cached by GetImagesMatchingFilter when a label filter forces it.
+type ResolvedInfo struct {
+	Info   *ociv1.Image
+	Digest digest.Digest
+	Size   int64
+}
+
+// FilterParams holds the predicates parsed out of one or more -f/--filter
+// flags on `kpod images`. Predicates sharing a key (e.g. two "label=" flags)
+// are OR'd together; distinct keys are AND'd by GetImagesMatchingFilter.
+type FilterParams struct {
+	dangling          []string
+	labels            []string
+	referencePatterns []string
+	beforeImages      []string
+	sinceImages       []string
+	readonly          []string
+}
+
+// ParseFilter parses the string value of each -f/--filter flag (one per
+// occurrence) into a FilterParams. Recognized keys are dangling, label,
+// reference, before, since, and readonly; an unrecognized key is an error.
+func ParseFilter(store storage.Store, filters []string) (*FilterParams, error) {
+	params := &FilterParams{}
+	for _, filter := range filters {
+		key, value, err := splitFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "dangling":
+			params.dangling = append(params.dangling, value)
+		case "label":
+			params.labels = append(params.labels, value)
+		case "reference":
+			params.referencePatterns = append(params.referencePatterns, value)
+		case "before":
+			params.beforeImages = append(params.beforeImages, value)
+		case "since":
+			params.sinceImages = append(params.sinceImages, value)
+		case "readonly":
+			params.readonly = append(params.readonly, value)
+		default:
+			return nil, errors.Errorf("invalid filter %q: unknown key %q", filter, key)
+		}
+	}
+	return params, nil
+}
+
+func splitFilter(filter string) (key, value string, err error) {
+	parts := strings.SplitN(filter, "=", 2)
+	key = parts[0]
+	if key == "" {
+		return "", "", errors.Errorf("invalid filter %q: missing key", filter)
+	}
+	if len(parts) == 2 {
+		value = parts[1]
+	}
+	return key, value, nil
+}
+
+// GetImagesMatchingFilter returns the images in store whose ID, ID prefix, or
+// one of whose names matches name (when non-empty) and which satisfy every predicate group in params, along
+// with whatever ResolvedInfo it resolved along the way, keyed by image ID.
+func GetImagesMatchingFilter(store storage.Store, params *FilterParams, name string) ([]storage.Image, map[string]ResolvedInfo, error) {
+	images, err := store.Images()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error reading images")
+	}
+
+	var resolved map[string]ResolvedInfo
+	if params != nil && len(params.labels) > 0 {
+		resolved, err = fetchLabelInfo(store, images)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	matched := make([]storage.Image, 0, len(images))
+	for _, img := range images {
+		if name != "" && !matchesName(img, name) {
+			continue
+		}
+		if params != nil {
+			ok, err := params.matches(store, img, resolved)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, img)
+	}
+	return matched, resolved, nil
+}
+
+// fetchLabelInfo resolves every image's manifest in parallel, across a
+// worker pool bounded by runtime.NumCPU(), so a label= filter pays for the
+// walk once per image rather than serially re-walking it.
+func fetchLabelInfo(store storage.Store, images []storage.Image) (map[string]ResolvedInfo, error) {
+	resolved := make([]ResolvedInfo, len(images))
+	errs := make([]error, len(images))
+
+	sem := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for i, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, img storage.Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info, imgDigest, size, err := InfoAndDigestAndSize(store, img)
+			resolved[i] = ResolvedInfo{Info: info, Digest: imgDigest, Size: size}
+			errs[i] = err
+		}(i, img)
+	}
+	wg.Wait()
+
+	result := make(map[string]ResolvedInfo, len(images))
+	for i, img := range images {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "error reading labels for image %s", img.ID)
+		}
+		result[img.ID] = resolved[i]
+	}
+	return result, nil
+}
+
+func matchesName(img storage.Image, name string) bool {
+	if img.ID == name || strings.HasPrefix(img.ID, name) {
+		return true
+	}
+	for _, n := range img.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// imageByID is the narrow slice of storage.Store that matchesAnyBefore and
+// matchesAnySince need, so tests can fake it.
+type imageByID interface {
+	Image(id string) (*storage.Image, error)
+}
+
+// matches reports whether img satisfies every non-empty predicate group in
+// p, ORing together the values given for a single key.
+func (p *FilterParams) matches(store storage.Store, img storage.Image, resolved map[string]ResolvedInfo) (bool, error) {
+	if len(p.labels) > 0 {
+		ok, err := matchesAnyLabel(resolved[img.ID].Info, p.labels)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if len(p.beforeImages) > 0 {
+		ok, err := matchesAnyBefore(store, img, p.beforeImages)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	if len(p.sinceImages) > 0 {
+		ok, err := matchesAnySince(store, img, p.sinceImages)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	groups := []struct {
+		values []string
+		match  func(store storage.Store, img storage.Image, values []string) (bool, error)
+	}{
+		{p.dangling, matchesAnyDangling},
+		{p.referencePatterns, matchesAnyReference},
+		{p.readonly, matchesAnyReadonly},
+	}
+	for _, g := range groups {
+		if len(g.values) == 0 {
+			continue
+		}
+		ok, err := g.match(store, img, g.values)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchesAnyDangling matches images with no names (dangling=true) or at
+// least one name (dangling=false).
+func matchesAnyDangling(store storage.Store, img storage.Image, values []string) (bool, error) {
+	isDangling := len(img.Names) == 0
+	for _, v := range values {
+		want, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid dangling filter value %q", v)
+		}
+		if want == isDangling {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyLabel matches "label=<k>" (key present) or "label=<k>=<v>" (key
+// present with that exact value) against the image's already-resolved config
+// labels (see fetchLabelInfo).
+func matchesAnyLabel(info *ociv1.Image, values []string) (bool, error) {
+	if info == nil {
+		return false, nil
+	}
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		val, ok := info.Config.Labels[parts[0]]
+		if !ok {
+			continue
+		}
+		if len(parts) == 1 || parts[1] == val {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyReference glob-matches patterns (docker/filepath.Match syntax)
+// against every repo:tag name the image carries.
+func matchesAnyReference(store storage.Store, img storage.Image, patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		for _, name := range img.Names {
+			matched, err := filepath.Match(pattern, name)
+			if err != nil {
+				return false, errors.Wrapf(err, "invalid reference filter pattern %q", pattern)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyBefore matches images created before any of the named images.
+func matchesAnyBefore(store imageByID, img storage.Image, refs []string) (bool, error) {
+	for _, ref := range refs {
+		refImg, err := store.Image(ref)
+		if err != nil {
+			return false, errors.Wrapf(err, "before filter: unknown image %q", ref)
+		}
+		if img.Created.Before(refImg.Created) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAnySince matches images created after any of the named images.
+func matchesAnySince(store imageByID, img storage.Image, refs []string) (bool, error) {
+	for _, ref := range refs {
+		refImg, err := store.Image(ref)
+		if err != nil {
+			return false, errors.Wrapf(err, "since filter: unknown image %q", ref)
+		}
+		if img.Created.After(refImg.Created) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyReadonly matches images belonging to a read-only additional
+// image store (readonly=true) or the primary, writable store (readonly=false).
+func matchesAnyReadonly(store storage.Store, img storage.Image, values []string) (bool, error) {
+	primary, err := store.ImageStore()
+	if err != nil {
+		return false, errors.Wrapf(err, "error resolving primary image store")
+	}
+	_, getErr := primary.Get(img.ID)
+	readOnly, err := readOnlyFromPrimaryGetErr(getErr)
+	if err != nil {
+		return false, errors.Wrapf(err, "error checking primary image store for %s", img.ID)
+	}
+	for _, v := range values {
+		want, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid readonly filter value %q", v)
+		}
+		if want == readOnly {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readOnlyFromPrimaryGetErr interprets the error from the primary image
+// store's Get: not found means the image lives in a read-only additional
+// store, nil means it's in the primary store, and anything else is a real
+// error that should propagate rather than be read as readonly=true.
+func readOnlyFromPrimaryGetErr(getErr error) (bool, error) {
+	switch errors.Cause(getErr) {
+	case nil:
+		return false, nil
+	case storage.ErrImageUnknown:
+		return true, nil
+	default:
+		return false, getErr
+	}
+}
@@ -0,0 +1,177 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containers/storage"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// fakeImageStore lets matchesAnyBefore/matchesAnySince be exercised without
+// a real storage.Store, by resolving a ref to a canned *storage.Image.
+type fakeImageStore struct {
+	images map[string]*storage.Image
+}
+
+func (f fakeImageStore) Image(id string) (*storage.Image, error) {
+	return f.images[id], nil
+}
+
+func img(id string, names []string, created time.Time) storage.Image {
+	return storage.Image{ID: id, Names: names, Created: created}
+}
+
+func TestMatchesAnyDanglingTrue(t *testing.T) {
+	ok, err := matchesAnyDangling(nil, img("a", nil, time.Now()), []string{"true"})
+	if err != nil || !ok {
+		t.Fatalf("dangling=true on untagged image: ok=%v err=%v, want true, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyDanglingTrueRejectsTaggedImage(t *testing.T) {
+	ok, err := matchesAnyDangling(nil, img("a", []string{"a:latest"}, time.Now()), []string{"true"})
+	if err != nil || ok {
+		t.Fatalf("dangling=true on tagged image: ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyLabelKeyOnly(t *testing.T) {
+	info := &ociv1.Image{}
+	info.Config.Labels = map[string]string{"stage": "prod"}
+
+	ok, err := matchesAnyLabel(info, []string{"stage"})
+	if err != nil || !ok {
+		t.Fatalf("label=stage: ok=%v err=%v, want true, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyLabelKeyValue(t *testing.T) {
+	info := &ociv1.Image{}
+	info.Config.Labels = map[string]string{"stage": "prod"}
+
+	if ok, err := matchesAnyLabel(info, []string{"stage=dev"}); err != nil || ok {
+		t.Fatalf("label=stage=dev: ok=%v err=%v, want false, nil", ok, err)
+	}
+	if ok, err := matchesAnyLabel(info, []string{"stage=prod"}); err != nil || !ok {
+		t.Fatalf("label=stage=prod: ok=%v err=%v, want true, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyLabelNilInfo(t *testing.T) {
+	ok, err := matchesAnyLabel(nil, []string{"stage"})
+	if err != nil || ok {
+		t.Fatalf("label filter on image with no resolvable config: ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyReferenceGlob(t *testing.T) {
+	image := img("a", []string{"example.com/foo:latest", "example.com/bar:v1"}, time.Now())
+
+	ok, err := matchesAnyReference(nil, image, []string{"example.com/foo:*"})
+	if err != nil || !ok {
+		t.Fatalf("reference=example.com/foo:*: ok=%v err=%v, want true, nil", ok, err)
+	}
+
+	ok, err = matchesAnyReference(nil, image, []string{"other.com/*:*"})
+	if err != nil || ok {
+		t.Fatalf("reference=other.com/*:*: ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestMatchesAnyBeforeAndSinceOrdering(t *testing.T) {
+	now := time.Now()
+	ref := img("ref", []string{"ref:latest"}, now)
+	store := fakeImageStore{images: map[string]*storage.Image{"ref": &ref}}
+
+	older := img("older", nil, now.Add(-time.Hour))
+	newer := img("newer", nil, now.Add(time.Hour))
+
+	if ok, err := matchesAnyBefore(store, older, []string{"ref"}); err != nil || !ok {
+		t.Fatalf("before=ref on older image: ok=%v err=%v, want true, nil", ok, err)
+	}
+	if ok, err := matchesAnyBefore(store, newer, []string{"ref"}); err != nil || ok {
+		t.Fatalf("before=ref on newer image: ok=%v err=%v, want false, nil", ok, err)
+	}
+	if ok, err := matchesAnySince(store, newer, []string{"ref"}); err != nil || !ok {
+		t.Fatalf("since=ref on newer image: ok=%v err=%v, want true, nil", ok, err)
+	}
+	if ok, err := matchesAnySince(store, older, []string{"ref"}); err != nil || ok {
+		t.Fatalf("since=ref on older image: ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestFilterParamsMatchesORsWithinAKey(t *testing.T) {
+	p := &FilterParams{referencePatterns: []string{"nomatch:*", "foo:*"}}
+	image := img("a", []string{"foo:latest"}, time.Now())
+
+	ok, err := p.matches(nil, image, nil)
+	if err != nil || !ok {
+		t.Fatalf("two reference patterns, one matching: ok=%v err=%v, want true, nil", ok, err)
+	}
+}
+
+func TestFilterParamsMatchesANDsAcrossKeys(t *testing.T) {
+	image := img("a", nil, time.Now())
+	labelInfo := map[string]ResolvedInfo{"a": {Info: &ociv1.Image{}}}
+	labelInfo["a"].Info.Config.Labels = map[string]string{"stage": "prod"}
+
+	matchingLabel := &FilterParams{dangling: []string{"true"}, labels: []string{"stage=prod"}}
+	if ok, err := matchingLabel.matches(nil, image, labelInfo); err != nil || !ok {
+		t.Fatalf("dangling=true AND label=stage=prod (both true): ok=%v err=%v, want true, nil", ok, err)
+	}
+
+	mismatchedLabel := &FilterParams{dangling: []string{"true"}, labels: []string{"stage=dev"}}
+	if ok, err := mismatchedLabel.matches(nil, image, labelInfo); err != nil || ok {
+		t.Fatalf("dangling=true AND label=stage=dev (one false): ok=%v err=%v, want false, nil", ok, err)
+	}
+}
+
+func TestParseFilterUnknownKey(t *testing.T) {
+	if _, err := ParseFilter(nil, []string{"bogus=1"}); err == nil {
+		t.Fatal("ParseFilter with unknown key: got nil error, want an error")
+	}
+}
+
+func TestParseFilterGroupsByKey(t *testing.T) {
+	params, err := ParseFilter(nil, []string{"label=a", "label=b", "dangling=true"})
+	if err != nil {
+		t.Fatalf("ParseFilter: unexpected error %v", err)
+	}
+	if len(params.labels) != 2 || params.labels[0] != "a" || params.labels[1] != "b" {
+		t.Fatalf("labels = %v, want [a b]", params.labels)
+	}
+	if len(params.dangling) != 1 || params.dangling[0] != "true" {
+		t.Fatalf("dangling = %v, want [true]", params.dangling)
+	}
+}
+
+func TestReadOnlyFromPrimaryGetErrFoundInPrimary(t *testing.T) {
+	readOnly, err := readOnlyFromPrimaryGetErr(nil)
+	if err != nil || readOnly {
+		t.Fatalf("nil Get error: readOnly=%v err=%v, want false, nil", readOnly, err)
+	}
+}
+
+func TestReadOnlyFromPrimaryGetErrNotFound(t *testing.T) {
+	readOnly, err := readOnlyFromPrimaryGetErr(storage.ErrImageUnknown)
+	if err != nil || !readOnly {
+		t.Fatalf("ErrImageUnknown: readOnly=%v err=%v, want true, nil", readOnly, err)
+	}
+}
+
+func TestReadOnlyFromPrimaryGetErrWrappedNotFound(t *testing.T) {
+	readOnly, err := readOnlyFromPrimaryGetErr(errors.Wrap(storage.ErrImageUnknown, "looking up image"))
+	if err != nil || !readOnly {
+		t.Fatalf("wrapped ErrImageUnknown: readOnly=%v err=%v, want true, nil", readOnly, err)
+	}
+}
+
+func TestReadOnlyFromPrimaryGetErrOtherErrorPropagates(t *testing.T) {
+	storeErr := errors.New("store is unavailable")
+	readOnly, err := readOnlyFromPrimaryGetErr(storeErr)
+	if err != storeErr || readOnly {
+		t.Fatalf("unrelated error: readOnly=%v err=%v, want false, %v", readOnly, err, storeErr)
+	}
+}
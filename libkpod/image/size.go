@@ -0,0 +1,30 @@
+package image
+
+import "github.com/containers/storage"
+
+// layerByID is the narrow slice of storage.Store that CachedSize needs, so
+// tests can fake it.
+type layerByID interface {
+	Layer(id string) (*storage.Layer, error)
+}
+
+// CachedSize returns the size containers/storage already has cached for img,
+// summing the UncompressedSize of every layer in its chain from TopLayer up
+// through each Parent, avoiding a manifest walk. It reports false if there is
+// no top layer or any layer in the chain hasn't computed its uncompressed
+// size yet, in which case the caller should fall back to InfoAndDigestAndSize.
+func CachedSize(store layerByID, img storage.Image) (int64, bool) {
+	if img.TopLayer == "" {
+		return 0, false
+	}
+	var total int64
+	for id := img.TopLayer; id != ""; {
+		layer, err := store.Layer(id)
+		if err != nil || layer.UncompressedSize < 0 {
+			return 0, false
+		}
+		total += layer.UncompressedSize
+		id = layer.Parent
+	}
+	return total, true
+}
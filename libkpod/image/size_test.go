@@ -0,0 +1,50 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/containers/storage"
+	"github.com/pkg/errors"
+)
+
+type fakeLayerStore struct {
+	layers map[string]*storage.Layer
+}
+
+func (f fakeLayerStore) Layer(id string) (*storage.Layer, error) {
+	layer, ok := f.layers[id]
+	if !ok {
+		return nil, errors.Errorf("no such layer %q", id)
+	}
+	return layer, nil
+}
+
+func TestCachedSizeSumsLayerChain(t *testing.T) {
+	store := fakeLayerStore{layers: map[string]*storage.Layer{
+		"top":  {ID: "top", Parent: "mid", UncompressedSize: 100},
+		"mid":  {ID: "mid", Parent: "base", UncompressedSize: 50},
+		"base": {ID: "base", UncompressedSize: 25},
+	}}
+
+	size, cached := CachedSize(store, storage.Image{TopLayer: "top"})
+	if !cached || size != 175 {
+		t.Fatalf("CachedSize = %d, %v, want 175, true", size, cached)
+	}
+}
+
+func TestCachedSizeFalseWhenChainHasUncomputedLayer(t *testing.T) {
+	store := fakeLayerStore{layers: map[string]*storage.Layer{
+		"top": {ID: "top", Parent: "mid", UncompressedSize: 100},
+		"mid": {ID: "mid", UncompressedSize: -1},
+	}}
+
+	if _, cached := CachedSize(store, storage.Image{TopLayer: "top"}); cached {
+		t.Fatalf("CachedSize reported cached despite an uncomputed layer in the chain")
+	}
+}
+
+func TestCachedSizeFalseWhenNoTopLayer(t *testing.T) {
+	if _, cached := CachedSize(fakeLayerStore{}, storage.Image{}); cached {
+		t.Fatalf("CachedSize reported cached for an image with no top layer")
+	}
+}